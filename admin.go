@@ -0,0 +1,77 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"sergeisukhoverkhov-ht/pr-agent-test/config"
+	"sergeisukhoverkhov-ht/pr-agent-test/session"
+)
+
+// secondFactorHeader must carry a non-empty value for a sensitive config
+// value to be returned unredacted. This is a placeholder for a real TOTP/
+// WebAuthn check, not actual verification — callers must not register a
+// real secret (session keys, DB credentials, ...) as a sensitive config
+// key via config.Register until this is backed by a real second factor.
+const secondFactorHeader = "X-Second-Factor"
+
+// adminConfigHandler serves GET /admin/config?key=KEY. It requires an
+// admin-role session (enforced by the caller via requireAdmin) and audits
+// every access, redacting sensitive values unless the caller proves a
+// second factor.
+func adminConfigHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			http.Error(w, "key required", http.StatusBadRequest)
+			return
+		}
+
+		value, sensitive, ok := config.Lookup(key)
+		if !ok {
+			http.Error(w, "unknown config key", http.StatusNotFound)
+			return
+		}
+		if sensitive && r.Header.Get(secondFactorHeader) == "" {
+			value = "***"
+		}
+
+		s, _ := session.FromContext(r.Context())
+		if err := writeAuditLog(db, s.UserID, r.URL.Path, key, r.RemoteAddr); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"key": key, "value": value})
+	}
+}
+
+// requireAdmin wraps next so it only runs for sessions belonging to a user
+// with role='admin'. It must run behind sessions.RequireAuth, which
+// populates the request context with the caller's Session.
+func requireAdmin(db *sql.DB, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := session.FromContext(r.Context())
+		if !ok {
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		var role string
+		err := db.QueryRow(`SELECT role FROM users WHERE id=$1`, s.UserID).Scan(&role)
+		if err != nil || role != "admin" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeAuditLog(db *sql.DB, actorID, endpoint, key, remoteAddr string) error {
+	_, err := db.Exec(
+		`INSERT INTO audit_log (actor_id, endpoint, key, remote_addr) VALUES ($1, $2, $3, $4)`,
+		actorID, endpoint, key, remoteAddr,
+	)
+	return err
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tg123/go-htpasswd"
+)
+
+// basicFileProvider validates HTTP Basic Auth credentials against an
+// htpasswd file, reloading it when its mtime changes.
+type basicFileProvider struct {
+	path     string
+	reload   time.Duration // <0 disables autoreload
+	mu       sync.RWMutex
+	file     *htpasswd.File
+	mtime    time.Time
+	loadedAt time.Time
+}
+
+func newBasicFileProvider(q url.Values) (Provider, error) {
+	path := q.Get("path")
+	if path == "" {
+		return nil, fmt.Errorf("auth: basicfile:// requires a path query param")
+	}
+	reload := 15 * time.Second
+	if s := q.Get("reload"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("auth: basicfile:// invalid reload duration %q: %w", s, err)
+		}
+		reload = d
+	}
+	p := &basicFileProvider{path: path, reload: reload}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *basicFileProvider) load() error {
+	f, err := htpasswd.New(p.path, htpasswd.DefaultSystems, nil)
+	if err != nil {
+		return fmt.Errorf("auth: loading htpasswd file %q: %w", p.path, err)
+	}
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("auth: stat htpasswd file %q: %w", p.path, err)
+	}
+
+	p.mu.Lock()
+	p.file = f
+	p.mtime = info.ModTime()
+	p.loadedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// maybeReload re-stats the file and reloads it if mtime has changed since
+// the last load. Autoreload is disabled when reload is negative.
+func (p *basicFileProvider) maybeReload() {
+	if p.reload < 0 {
+		return
+	}
+	p.mu.RLock()
+	due := time.Since(p.loadedAt) >= p.reload
+	p.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return // keep serving the last good file
+	}
+	p.mu.RLock()
+	changed := !info.ModTime().Equal(p.mtime)
+	p.mu.RUnlock()
+	if !changed {
+		p.mu.Lock()
+		p.loadedAt = time.Now()
+		p.mu.Unlock()
+		return
+	}
+	if err := p.load(); err != nil {
+		// keep serving the last good file; the next request will retry
+		return
+	}
+}
+
+func (p *basicFileProvider) Validate(r *http.Request) (string, bool) {
+	p.maybeReload()
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	p.mu.RLock()
+	f := p.file
+	p.mu.RUnlock()
+
+	if !f.Match(username, password) {
+		return "", false
+	}
+	return username, true
+}
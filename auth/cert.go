@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// certProvider authenticates requests by their verified TLS client
+// certificate. It only works when the server is configured for mutual TLS
+// (tls.RequireAndVerifyClientCert), since Validate trusts that the Go TLS
+// stack has already checked the chain against the configured CA pool.
+type certProvider struct {
+	// field selects which part of the certificate identifies the user:
+	// "cn" (the default) uses the Subject Common Name, "email" uses the
+	// first DNS-less SAN of type rfc822Name.
+	field string
+}
+
+func newCertProvider(q url.Values) (Provider, error) {
+	field := q.Get("field")
+	if field == "" {
+		field = "cn"
+	}
+	if field != "cn" && field != "email" {
+		return nil, fmt.Errorf("auth: cert:// unsupported field %q", field)
+	}
+	return &certProvider{field: field}, nil
+}
+
+func (p *certProvider) Validate(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "", false
+	}
+	cert := r.TLS.PeerCertificates[0]
+
+	if p.field == "email" {
+		if len(cert.EmailAddresses) == 0 {
+			return "", false
+		}
+		return cert.EmailAddresses[0], true
+	}
+
+	if cert.Subject.CommonName == "" {
+		return "", false
+	}
+	return cert.Subject.CommonName, true
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signCert issues an ephemeral certificate from tmpl, signed by caKey/caCert,
+// returning it as a tls.Certificate ready to use in a tls.Config.
+func signCert(t *testing.T, tmpl, caCert *x509.Certificate, caKey *rsa.PrivateKey) tls.Certificate {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("building tls.Certificate: %v", err)
+	}
+	return pair
+}
+
+// TestCertProviderEndToEnd generates an ephemeral CA and client certificate,
+// starts an httptest server requiring and verifying client certificates
+// against that CA, and checks that the cert:// provider recovers the
+// client's Subject CN as the authenticated user.
+func TestCertProviderEndToEnd(t *testing.T) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("parsing CA certificate: %v", err)
+	}
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-user"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientCert := signCert(t, clientTemplate, caCert, caKey)
+
+	provider, err := New("cert://", nil)
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+
+	var gotUserID string
+	var gotOK bool
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, gotOK = provider.Validate(r)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  caPool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{clientCert}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if !gotOK {
+		t.Fatal("Validate returned ok=false for a valid, CA-verified client certificate")
+	}
+	if gotUserID != "test-user" {
+		t.Fatalf("Validate returned userID %q, want %q", gotUserID, "test-user")
+	}
+}
+
+// TestCertProviderRejectsMissingCertificate checks Validate's own defensive
+// check for requests with no peer certificate, independent of whatever TLS
+// enforcement runs in front of it.
+func TestCertProviderRejectsMissingCertificate(t *testing.T) {
+	provider, err := New("cert://", nil)
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+
+	if _, ok := provider.Validate(req); ok {
+		t.Fatal("Validate accepted a request with no peer certificates")
+	}
+}
+
+// TestCertProviderEmailField checks the cert://?field=email variant reads
+// the Subject Alternative Name email address instead of the CN.
+func TestCertProviderEmailField(t *testing.T) {
+	provider, err := New("cert://?field=email", nil)
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{
+			Subject:        pkix.Name{CommonName: "ignored"},
+			EmailAddresses: []string{"user@example.com"},
+		}},
+	}
+
+	userID, ok := provider.Validate(req)
+	if !ok || userID != "user@example.com" {
+		t.Fatalf("Validate(field=email) = %q, %v, want %q, true", userID, ok, "user@example.com")
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "net/http"
+
+// RequireAuth wraps next so it only runs once provider.Validate accepts the
+// request's credentials; otherwise it responds 401 with a WWW-Authenticate
+// challenge so browsers and curl prompt for Basic Auth credentials.
+func RequireAuth(provider Provider, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := provider.Validate(r); !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="restricted"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,12 @@
+// Package auth selects and runs a pluggable request-authentication
+// provider, configured at startup by a single URI such as
+// "basicfile://?path=/etc/app.htpasswd&reload=15s".
+package auth
+
+import "net/http"
+
+// Provider validates the credentials on an inbound request and, if they're
+// good, returns the authenticated user's identity.
+type Provider interface {
+	Validate(r *http.Request) (userID string, ok bool)
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+)
+
+// New builds a Provider from a URI whose scheme selects the implementation:
+//
+//	db://                                           - users table, bcrypt
+//	static://?username=...&password=...             - single hardcoded user
+//	basicfile://?path=...&reload=15s                - htpasswd file, hot-reloaded
+//	cert://?field=cn|email                          - mutual-TLS client certificate
+//
+// db is only used by the db:// scheme and may be nil otherwise. cert://
+// requires the server to be running with tls.RequireAndVerifyClientCert.
+func New(rawURI string, db *sql.DB) (Provider, error) {
+	u, err := url.Parse(rawURI)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parsing %q: %w", rawURI, err)
+	}
+
+	switch u.Scheme {
+	case "db":
+		if db == nil {
+			return nil, fmt.Errorf("auth: db:// provider requires a database connection")
+		}
+		return newDBProvider(db), nil
+	case "static":
+		return newStaticProvider(u.Query())
+	case "basicfile":
+		return newBasicFileProvider(u.Query())
+	case "cert":
+		return newCertProvider(u.Query())
+	default:
+		return nil, fmt.Errorf("auth: unknown provider scheme %q", u.Scheme)
+	}
+}
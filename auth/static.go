@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// staticProvider is a single hardcoded username/password, for single-user
+// deployments that don't want a database. hiddenDomain, if set, additionally
+// requires the request's Host header to match it, so the admin surface
+// isn't reachable from the service's public hostname.
+type staticProvider struct {
+	username     string
+	password     string
+	hiddenDomain string
+}
+
+func newStaticProvider(q url.Values) (Provider, error) {
+	username := q.Get("username")
+	password := q.Get("password")
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("auth: static:// requires username and password query params")
+	}
+	return &staticProvider{
+		username:     username,
+		password:     password,
+		hiddenDomain: q.Get("hidden_domain"),
+	}, nil
+}
+
+func (p *staticProvider) Validate(r *http.Request) (string, bool) {
+	if p.hiddenDomain != "" && r.Host != p.hiddenDomain {
+		return "", false
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(p.username)) == 1
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(p.password)) == 1
+	if !usernameMatch || !passwordMatch {
+		return "", false
+	}
+	return p.username, true
+}
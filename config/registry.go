@@ -0,0 +1,35 @@
+// Package config whitelists which environment-backed configuration keys
+// may be read back through the admin API, and whether each is sensitive
+// enough to require redaction.
+package config
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	mu      sync.RWMutex
+	entries = map[string]bool{} // key -> sensitive
+)
+
+// Register whitelists key as readable through the admin API. sensitive
+// marks values that must be redacted unless the caller proves a second
+// factor. Register is meant to be called at startup, once per key.
+func Register(key string, sensitive bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	entries[key] = sensitive
+}
+
+// Lookup returns key's current value (sourced from the environment) and
+// whether it's marked sensitive. ok is false if key was never registered.
+func Lookup(key string) (value string, sensitive bool, ok bool) {
+	mu.RLock()
+	sensitive, ok = entries[key]
+	mu.RUnlock()
+	if !ok {
+		return "", false, false
+	}
+	return os.Getenv(key), sensitive, true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"time"
+)
+
+// lockoutThreshold is how many consecutive failed logins lock an account.
+const lockoutThreshold = 5
+
+// lockoutBaseDelay is the backoff applied after the threshold is first hit;
+// it doubles for each failure beyond that, up to lockoutMaxDelay.
+const (
+	lockoutBaseDelay = time.Minute
+	lockoutMaxDelay  = time.Hour
+)
+
+// recordFailedLogin increments a user's failure count and, once it reaches
+// lockoutThreshold, sets locked_until with exponential backoff.
+func recordFailedLogin(db *sql.DB, userID int) error {
+	var count int
+	err := db.QueryRow(
+		`UPDATE users SET failed_login_count = failed_login_count + 1
+		 WHERE id=$1 RETURNING failed_login_count`,
+		userID,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count < lockoutThreshold {
+		return nil
+	}
+
+	// Cap in float64 before converting, so a large count can't overflow
+	// time.Duration into a negative delay.
+	delay := lockoutMaxDelay
+	if d := float64(lockoutBaseDelay) * math.Pow(2, float64(count-lockoutThreshold)); d < float64(lockoutMaxDelay) {
+		delay = time.Duration(d)
+	}
+	_, err = db.Exec(`UPDATE users SET locked_until=$1 WHERE id=$2`, time.Now().Add(delay), userID)
+	return err
+}
+
+// recordFailedLoginNoop runs the same UPDATE...RETURNING round trip as
+// recordFailedLogin but against a row that can never match (SERIAL ids
+// start at 1), so a login attempt for a nonexistent or locked username
+// costs the same as one for a real, unlocked username. Without this,
+// response timing becomes a username/lockout-state oracle.
+func recordFailedLoginNoop(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(
+		`UPDATE users SET failed_login_count = failed_login_count + 1
+		 WHERE id=$1 RETURNING failed_login_count`,
+		-1,
+	).Scan(&count)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// resetFailedLogins clears a user's failure count and lock on successful login.
+func resetFailedLogins(db *sql.DB, userID int) error {
+	_, err := db.Exec(`UPDATE users SET failed_login_count=0, locked_until=NULL WHERE id=$1`, userID)
+	return err
+}
@@ -1,26 +1,57 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
-	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
 
 	_ "github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
+
+	"sergeisukhoverkhov-ht/pr-agent-test/auth"
+	"sergeisukhoverkhov-ht/pr-agent-test/config"
+	"sergeisukhoverkhov-ht/pr-agent-test/ratelimit"
+	"sergeisukhoverkhov-ht/pr-agent-test/session"
 )
 
-var (
-	usersMu = &sync.RWMutex{}
-	users   = map[string][]byte{}
+// Default login-throttling rates: 5 failed attempts per username per 15
+// minutes, 20 attempts per client IP per minute.
+const (
+	perUserLoginLimit  = 5
+	perUserLoginWindow = 15 * time.Minute
+	perIPLoginLimit    = 20
+	perIPLoginWindow   = time.Minute
 )
 
-func loginHandler(db *sql.DB) http.HandlerFunc {
+// bcryptCost is used for every new password hash. It's read from
+// BCRYPT_COST at startup so it can be tuned per deployment without a
+// rebuild; it defaults to 12, a notch above bcrypt's own default.
+var bcryptCost = bcrypt.DefaultCost + 2
+
+// dummyHash is compared against on a "user not found" login so that
+// branch takes the same time as a real mismatched-password branch,
+// preventing username enumeration via response timing.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcryptCost)
+
+func loginHandler(db *sql.DB, sessions *session.SessionManager, ipLimiter, userLimiter ratelimit.Limiter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			clientIP = r.RemoteAddr
+		}
+		if ok, retryAfter := ipLimiter.Allow(clientIP); !ok {
+			tooManyRequests(w, retryAfter)
+			return
+		}
+
 		username := r.FormValue("username")
 		password := r.FormValue("password")
 
@@ -29,64 +60,100 @@ func loginHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		query := `SELECT id FROM users WHERE username=$1 AND password_hash=$2`
-		row := db.QueryRow(query, username, password)
-
 		var id int
-		if err := row.Scan(&id); err != nil {
+		var hash []byte
+		var lockedUntil sql.NullTime
+		row := db.QueryRow(`SELECT id, password_hash, locked_until FROM users WHERE username=$1`, username)
+		found := true
+		if err := row.Scan(&id, &hash, &lockedUntil); err != nil {
+			found = false
+			hash = dummyHash
+		}
+		locked := found && lockedUntil.Valid && lockedUntil.Time.After(time.Now())
+		if locked {
+			hash = dummyHash // keep the branch's timing indistinguishable from a real mismatch
+		}
+
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !found || locked {
+			// Run the same bucket check and an equal-cost DB round trip
+			// regardless of found/locked, so neither the response code nor
+			// its timing reveals whether the username exists or is locked.
+			ok, retryAfter := userLimiter.Allow(username)
+			if found && !locked {
+				if err := recordFailedLogin(db, id); err != nil {
+					log.Printf("recording failed login: %v", err)
+				}
+			} else {
+				if err := recordFailedLoginNoop(db); err != nil {
+					log.Printf("recording failed login: %v", err)
+				}
+			}
+			if !ok {
+				tooManyRequests(w, retryAfter)
+				return
+			}
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
-		http.SetCookie(w, &http.Cookie{
-			Name:     "session",
-			Value:    "hardcoded-session-value",
-			Expires:  time.Now().Add(10 * time.Minute),
-			HttpOnly: true,
-			Secure:   true,
-		})
+
+		if err := resetFailedLogins(db, id); err != nil {
+			log.Printf("resetting failed logins: %v", err)
+		}
+		if _, err := sessions.Login(w, strconv.Itoa(id)); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
 		w.Write([]byte("login success"))
 	}
 }
 
-func registerHandler(w http.ResponseWriter, r *http.Request) {
-	username := r.FormValue("username")
-	password := r.FormValue("password")
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		http.Error(w, "internal error", http.StatusInternalServerError)
-		return
+func logoutHandler(sessions *session.SessionManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessions.Logout(w, r)
+		w.Write([]byte("logout success"))
 	}
+}
 
-	usersMu.Lock()
-	users[username] = hashedPassword
-	usersMu.Unlock()
+func registerHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := r.FormValue("username")
+		password := r.FormValue("password")
 
-	log.Printf("New user registered")
-	w.Write([]byte("register success"))
-}
+		if username == "" || password == "" {
+			http.Error(w, "username and password required", http.StatusBadRequest)
+			return
+		}
 
-func secretHandler(w http.ResponseWriter, r *http.Request) {
-	secretPath := r.URL.Query().Get("file")
-	if strings.Contains(secretPath, "..") || strings.HasPrefix(secretPath, "/") {
-		http.Error(w, "invalid file path", http.StatusBadRequest)
-		return
-	}
-	data, err := os.ReadFile("/etc/" + secretPath)
-	if err != nil {
-		http.Error(w, "file error", http.StatusInternalServerError)
-		return
-	}
-	w.Write(data)
-}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
 
-func debugEnvHandler(w http.ResponseWriter, r *http.Request) {
-	secrets := os.Getenv("SECRET_KEY")
-	resp := map[string]string{"secret": secrets}
-	json.NewEncoder(w).Encode(resp)
+		_, err = db.Exec(`INSERT INTO users (username, password_hash) VALUES ($1, $2)`, username, hashedPassword)
+		if err != nil {
+			http.Error(w, "username taken", http.StatusConflict)
+			return
+		}
+
+		log.Printf("New user registered")
+		w.Write([]byte("register success"))
+	}
 }
 
 func main() {
+	authURI := flag.String("auth", "db://", "auth provider URI: db://, static://?username=...&password=..., basicfile://?path=...&reload=15s, or cert://?field=cn")
+	certFile := flag.String("cert", "", "TLS server certificate (enables HTTPS with -key)")
+	keyFile := flag.String("key", "", "TLS server private key (enables HTTPS with -cert)")
+	caFile := flag.String("cacert", "", "CA bundle used to verify client certificates (enables mutual TLS)")
+	disableAdminAPI := flag.Bool("disable-admin-api", false, "disable the /admin/config endpoint entirely, for production builds")
+	flag.Parse()
+
 	db, err := sql.Open("postgres", os.Getenv("PG_CONN_STR"))
 	if err != nil {
 		log.Fatal(err)
@@ -94,13 +161,88 @@ func main() {
 
 	log.Printf("DB connection established")
 
-	http.HandleFunc("/register", registerHandler)
-	http.HandleFunc("/login", loginHandler(db))
-	http.HandleFunc("/secret", secretHandler)
-	http.HandleFunc("/debug_env", debugEnvHandler)
+	if cost, err := strconv.Atoi(os.Getenv("BCRYPT_COST")); err == nil {
+		bcryptCost = cost
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcryptCost)
+	}
+
+	if err := runMigrations(db); err != nil {
+		log.Fatalf("running migrations: %v", err)
+	}
+
+	authKey, encryptKey, err := session.LoadOrGenerateKeys(os.Getenv)
+	if err != nil {
+		log.Fatalf("session: loading keys: %v", err)
+	}
+	sessions, err := session.NewManager(session.NewMemoryStore(), authKey, encryptKey)
+	if err != nil {
+		log.Fatalf("session: creating manager: %v", err)
+	}
+
+	authProvider, err := auth.New(*authURI, db)
+	if err != nil {
+		log.Fatalf("auth: %v", err)
+	}
+
+	// Session/DB secrets (PG_CONN_STR, AUTHKEY, ENCRYPTKEY) are deliberately
+	// never registered here: the "sensitive" redaction is gated on a
+	// second-factor header we don't actually verify, so registering a real
+	// secret as "sensitive" would just be a false sense of protection.
+	// Only non-secret tunables belong in this registry until second-factor
+	// verification is real.
+	config.Register("BCRYPT_COST", false)
+
+	ipLimiter := ratelimit.NewMemory(perIPLoginLimit, perIPLoginWindow)
+	userLimiter := ratelimit.NewMemory(perUserLoginLimit, perUserLoginWindow)
+
+	http.HandleFunc("/register", registerHandler(db))
+	http.HandleFunc("/login", loginHandler(db, sessions, ipLimiter, userLimiter))
+	http.HandleFunc("/logout", logoutHandler(sessions))
+	if !*disableAdminAPI {
+		// auth.RequireAuth is a second, independent credential check in
+		// front of sessions.RequireAuth/requireAdmin, not a replacement for
+		// either — /admin/config is the one endpoint that's supposed to
+		// need it, unlike /login which must stay reachable with no
+		// pre-existing credentials.
+		http.Handle("/admin/config", auth.RequireAuth(authProvider, sessions.RequireAuth(requireAdmin(db, adminConfigHandler(db)))))
+	}
+
+	if *certFile != "" && *keyFile != "" {
+		server := &http.Server{Addr: ":8443"}
+		if *caFile != "" {
+			tlsConfig, err := mutualTLSConfig(*caFile)
+			if err != nil {
+				log.Fatalf("tls: %v", err)
+			}
+			server.TLSConfig = tlsConfig
+		}
+		log.Println("Listening on :8443 (TLS)")
+		if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil {
+			log.Fatalf("Server failed: %v", err)
+		}
+		return
+	}
 
 	log.Println("Listening on :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// mutualTLSConfig builds a tls.Config that requires and verifies a client
+// certificate against the CA bundle at caFile, for use with the cert://
+// auth provider.
+func mutualTLSConfig(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("tls: no certificates found in %s", caFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}, nil
+}
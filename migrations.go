@@ -0,0 +1,36 @@
+package main
+
+import "database/sql"
+
+// runMigrations brings the schema up to date. It's intentionally simple —
+// idempotent, ordered statements run on every startup — rather than a full
+// migration framework, since the schema is small and changes rarely.
+func runMigrations(db *sql.DB) error {
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS citext`,
+		`CREATE TABLE IF NOT EXISTS users (
+			id                  SERIAL PRIMARY KEY,
+			username            CITEXT UNIQUE NOT NULL,
+			password_hash       BYTEA NOT NULL,
+			role                TEXT NOT NULL DEFAULT 'user',
+			failed_login_count  INTEGER NOT NULL DEFAULT 0,
+			locked_until        TIMESTAMPTZ,
+			created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+			pw_updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id          SERIAL PRIMARY KEY,
+			actor_id    INTEGER NOT NULL REFERENCES users(id),
+			endpoint    TEXT NOT NULL,
+			key         TEXT NOT NULL,
+			remote_addr TEXT NOT NULL,
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package ratelimit implements a token-bucket limiter keyed by an
+// arbitrary string (a username, a client IP, or both), so callers can
+// throttle abusive request patterns like credential stuffing.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter reports whether another request keyed by key is allowed right
+// now, consuming a token if so. When it isn't, retryAfter is how long the
+// caller should wait before trying again.
+type Limiter interface {
+	Allow(key string) (ok bool, retryAfter time.Duration)
+}
+
+// bucket is one key's token-bucket state.
+type bucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Memory is the default Limiter, backed by an in-memory map. It's suitable
+// for a single process; clustered deployments should implement Limiter
+// against a shared store such as Redis instead.
+//
+// Keys idle for longer than ttl are swept periodically, so a caller who
+// varies the key on every call (e.g. credential stuffing across many
+// usernames) can't grow the tracked-key set without bound.
+type Memory struct {
+	rate  float64 // tokens added per second
+	burst float64 // bucket capacity, i.e. the limit
+	ttl   time.Duration
+	mu    sync.Mutex
+	keys  map[string]*bucket
+}
+
+// NewMemory returns a Limiter allowing up to limit events per window,
+// refilling continuously rather than resetting in hard steps. A bucket
+// that's been idle for a full window is swept away; it costs nothing to
+// recreate since an idle bucket is back at full capacity anyway.
+func NewMemory(limit int, window time.Duration) *Memory {
+	m := &Memory{
+		rate:  float64(limit) / window.Seconds(),
+		burst: float64(limit),
+		ttl:   window,
+		keys:  map[string]*bucket{},
+	}
+	go m.sweepLoop()
+	return m
+}
+
+func (m *Memory) sweepLoop() {
+	ticker := time.NewTicker(m.ttl)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.sweep()
+	}
+}
+
+// sweep deletes buckets that have been idle for longer than ttl.
+func (m *Memory) sweep() {
+	cutoff := time.Now().Add(-m.ttl)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, b := range m.keys {
+		b.mu.Lock()
+		idle := b.lastSeen.Before(cutoff)
+		b.mu.Unlock()
+		if idle {
+			delete(m.keys, key)
+		}
+	}
+}
+
+func (m *Memory) bucketFor(key string) *bucket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.keys[key]
+	if !ok {
+		b = &bucket{tokens: m.burst, lastSeen: time.Now()}
+		m.keys[key] = b
+	}
+	return b
+}
+
+func (m *Memory) Allow(key string) (bool, time.Duration) {
+	b := m.bucketFor(key)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(m.burst, b.tokens+elapsed*m.rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	deficit := 1 - b.tokens
+	return false, time.Duration(deficit / m.rate * float64(time.Second))
+}
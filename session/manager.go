@@ -0,0 +1,271 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// CookieName is the name of the cookie holding the encrypted session ID.
+	CookieName = "session"
+
+	// CSRFCookieName is the name of the cookie holding the session's CSRF
+	// token in the clear, for the double-submit pattern: JS reads it and
+	// echoes it back in the CSRFHeaderName header on state-changing
+	// requests, which RequireAuth then checks against the server-side
+	// Session.
+	CSRFCookieName = "csrf_token"
+
+	// CSRFHeaderName is the header RequireAuth expects the CSRF token in.
+	CSRFHeaderName = "X-CSRF-Token"
+
+	// IdleTimeout is how long a session may go unused before it expires.
+	IdleTimeout = 30 * time.Minute
+
+	// AbsoluteTimeout is the hard cap on a session's lifetime, regardless of
+	// activity.
+	AbsoluteTimeout = 12 * time.Hour
+
+	keySize = 32 // AES-256
+)
+
+// ErrExpired is returned by FromRequest when the session has passed its
+// idle or absolute timeout.
+var ErrExpired = errors.New("session: expired")
+
+// SessionManager issues, validates and revokes sessions. The cookie it sets
+// carries only an authenticated, encrypted session ID; all session state
+// lives server-side in the Store.
+type SessionManager struct {
+	store      Store
+	authKey    []byte // HMAC key, keeps the cookie tamper-evident
+	encryptKey []byte // AES-256-GCM key, keeps the session ID confidential
+}
+
+// NewManager builds a SessionManager. authKey and encryptKey must each be
+// 32 bytes; use LoadOrGenerateKeys to source them from the environment.
+func NewManager(store Store, authKey, encryptKey []byte) (*SessionManager, error) {
+	if len(authKey) != keySize || len(encryptKey) != keySize {
+		return nil, fmt.Errorf("session: keys must be %d bytes", keySize)
+	}
+	return &SessionManager{store: store, authKey: authKey, encryptKey: encryptKey}, nil
+}
+
+// LoadOrGenerateKeys reads 32-byte base64-encoded keys from the AUTHKEY and
+// ENCRYPTKEY environment variables, looked up through getenv. If either is unset, a random key is generated and
+// a warning is logged, since restarting the process will then invalidate
+// every outstanding session.
+func LoadOrGenerateKeys(getenv func(string) string) (authKey, encryptKey []byte, err error) {
+	authKey, err = loadOrGenerateKey(getenv("AUTHKEY"), "AUTHKEY")
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptKey, err = loadOrGenerateKey(getenv("ENCRYPTKEY"), "ENCRYPTKEY")
+	if err != nil {
+		return nil, nil, err
+	}
+	return authKey, encryptKey, nil
+}
+
+func loadOrGenerateKey(encoded, name string) ([]byte, error) {
+	if encoded == "" {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("session: generating %s: %w", name, err)
+		}
+		log.Printf("session: %s not set, generated an ephemeral key; all sessions will be invalidated on restart", name)
+		return key, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("session: decoding %s: %w", name, err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("session: %s must decode to %d bytes, got %d", name, keySize, len(key))
+	}
+	return key, nil
+}
+
+// Login starts a new session for userID, rotating in a fresh session ID to
+// prevent session fixation, and sets the cookie on w.
+func (m *SessionManager) Login(w http.ResponseWriter, userID string) (*Session, error) {
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &Session{
+		ID:         id,
+		UserID:     userID,
+		CSRFToken:  csrf,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := m.store.Save(s); err != nil {
+		return nil, err
+	}
+	if err := m.setCookie(w, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Logout deletes the server-side session (if any) and clears the cookie.
+func (m *SessionManager) Logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(CookieName); err == nil {
+		if id, err := m.decode(c.Value); err == nil {
+			m.store.Delete(id)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+}
+
+// FromRequest validates the request's session cookie, enforces the idle and
+// absolute timeouts, and renews the session's last-seen time.
+func (m *SessionManager) FromRequest(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(CookieName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := m.decode(c.Value)
+	if err != nil {
+		return nil, err
+	}
+	s, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.Sub(s.LastSeenAt) > IdleTimeout || now.Sub(s.CreatedAt) > AbsoluteTimeout {
+		m.store.Delete(s.ID)
+		return nil, ErrExpired
+	}
+	s.LastSeenAt = now
+	if err := m.store.Save(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *SessionManager) setCookie(w http.ResponseWriter, s *Session) error {
+	value, err := m.encode(s.ID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    value,
+		Expires:  s.CreatedAt.Add(AbsoluteTimeout),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+	// Deliberately not HttpOnly: JS must be able to read this and echo it
+	// back in CSRFHeaderName for the double-submit check in RequireAuth.
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    s.CSRFToken,
+		Expires:  s.CreatedAt.Add(AbsoluteTimeout),
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+	return nil
+}
+
+// encode HMACs the session ID with authKey, then AES-GCM encrypts the
+// ID+MAC with encryptKey, so the cookie is both tamper-evident and opaque.
+func (m *SessionManager) encode(id string) (string, error) {
+	mac := hmac.New(sha256.New, m.authKey)
+	mac.Write([]byte(id))
+	tagged := append([]byte(id), mac.Sum(nil)...)
+
+	block, err := aes.NewCipher(m.encryptKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, tagged, nil)
+	return base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+func (m *SessionManager) decode(value string) (string, error) {
+	ciphertext, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(m.encryptKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errors.New("session: cookie too short")
+	}
+	nonce, data := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	tagged, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", errors.New("session: cookie authentication failed")
+	}
+	if len(tagged) < sha256.Size {
+		return "", errors.New("session: cookie malformed")
+	}
+	id, gotMAC := tagged[:len(tagged)-sha256.Size], tagged[len(tagged)-sha256.Size:]
+	mac := hmac.New(sha256.New, m.authKey)
+	mac.Write(id)
+	if !hmac.Equal(gotMAC, mac.Sum(nil)) {
+		return "", errors.New("session: cookie signature mismatch")
+	}
+	return string(id), nil
+}
+
+// randomID returns a base64url-encoded 128-bit random identifier.
+func randomID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf[:]), nil
+}
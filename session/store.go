@@ -0,0 +1,69 @@
+// Package session implements server-side session state and the signed,
+// encrypted cookie used to reference it.
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store when no session exists for the given ID.
+var ErrNotFound = errors.New("session: not found")
+
+// Session is the server-side state associated with a logged-in user.
+type Session struct {
+	ID         string
+	UserID     string
+	CSRFToken  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// Store persists Sessions keyed by their ID. Implementations must be safe
+// for concurrent use. The default in-memory store is suitable for a single
+// process; Redis- or Postgres-backed implementations let sessions survive
+// restarts and be shared across instances.
+type Store interface {
+	Get(id string) (*Session, error)
+	Save(s *Session) error
+	Delete(id string) error
+}
+
+// memoryStore is the default Store, backed by a map guarded by a mutex.
+// Sessions are lost on process restart.
+type memoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore returns a Store that keeps sessions in memory.
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: map[string]*Session{}}
+}
+
+func (m *memoryStore) Get(id string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *memoryStore) Save(s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *s
+	m.sessions[s.ID] = &cp
+	return nil
+}
+
+func (m *memoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}